@@ -0,0 +1,316 @@
+package edit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// This file implements the default Action methods bound by
+// defaultEmacsKeymap and defaultViNormalKeymap. They operate on the
+// Editor's in-progress line (ed.line, ed.pos), so they can be shared
+// between keymaps and mixed freely with user-registered bindings.
+
+// escTimeout is how long actionSearchHistory waits for the byte following
+// an Esc before concluding it was a bare Esc press rather than the start
+// of an escape sequence; a real sequence's bytes arrive in the same burst.
+const escTimeout = 25 * time.Millisecond
+
+func (ed *Editor) actionAcceptLine() error {
+	return errAcceptLine
+}
+
+func (ed *Editor) actionEOFOrDeleteForward() error {
+	if len(ed.line) == 0 {
+		return errEOF
+	}
+	return ed.actionDeleteForward()
+}
+
+func (ed *Editor) actionMoveLeft() error {
+	if ed.pos > 0 {
+		ed.pos--
+	} else {
+		ed.beep()
+	}
+	return nil
+}
+
+func (ed *Editor) actionMoveRight() error {
+	if ed.pos < len(ed.line) {
+		ed.pos++
+	} else {
+		ed.beep()
+	}
+	return nil
+}
+
+func (ed *Editor) actionMoveLineStart() error {
+	ed.pos = 0
+	return nil
+}
+
+func (ed *Editor) actionMoveLineEnd() error {
+	ed.pos = len(ed.line)
+	return nil
+}
+
+func (ed *Editor) actionForwardWord() error {
+	ed.pos = wordEndRunes(ed.line, ed.pos)
+	return nil
+}
+
+func (ed *Editor) actionBackWord() error {
+	ed.pos = wordStartRunes(ed.line, ed.pos)
+	return nil
+}
+
+func (ed *Editor) actionDeleteBack() error {
+	if ed.pos == 0 {
+		ed.beep()
+		return nil
+	}
+	ed.line = append(ed.line[:ed.pos-1], ed.line[ed.pos:]...)
+	ed.pos--
+	return nil
+}
+
+func (ed *Editor) actionDeleteForward() error {
+	if ed.pos >= len(ed.line) {
+		ed.beep()
+		return nil
+	}
+	ed.line = append(ed.line[:ed.pos], ed.line[ed.pos+1:]...)
+	return nil
+}
+
+func (ed *Editor) actionClearLine() error {
+	ed.line = nil
+	ed.pos = 0
+	return nil
+}
+
+func (ed *Editor) actionKillToEnd() error {
+	ed.killRing = append(ed.killRing, string(ed.line[ed.pos:]))
+	ed.line = ed.line[:ed.pos]
+	return nil
+}
+
+func (ed *Editor) actionKillWordBack() error {
+	start := wordStartRunes(ed.line, ed.pos)
+	ed.killRing = append(ed.killRing, string(ed.line[start:ed.pos]))
+	ed.line = append(ed.line[:start], ed.line[ed.pos:]...)
+	ed.pos = start
+	return nil
+}
+
+func (ed *Editor) actionYank() error {
+	if len(ed.killRing) == 0 {
+		ed.beep()
+		return nil
+	}
+	killed := []rune(ed.killRing[len(ed.killRing)-1])
+	line := make([]rune, 0, len(ed.line)+len(killed))
+	line = append(line, ed.line[:ed.pos]...)
+	line = append(line, killed...)
+	line = append(line, ed.line[ed.pos:]...)
+	ed.line = line
+	ed.pos += len(killed)
+	return nil
+}
+
+func (ed *Editor) actionClearScreen() error {
+	fmt.Fprint(ed.file, "\033[H\033[2J")
+	ed.writer.screen = newScreen()
+	return nil
+}
+
+func (ed *Editor) actionHistoryPrev() error {
+	ed.walkHistory(-1)
+	return nil
+}
+
+func (ed *Editor) actionHistoryNext() error {
+	ed.walkHistory(1)
+	return nil
+}
+
+func (ed *Editor) actionPaste() error {
+	text, err := readPaste(ed.stdin)
+	if err != nil {
+		return err
+	}
+	ed.insertText(text)
+	return nil
+}
+
+// actionViPendingD implements the first half of the "dd" vi command:
+// delete-line. A second 'd' clears the line; any other key cancels.
+func (ed *Editor) actionViPendingD() error {
+	r, err := ed.stdin.next()
+	if err != nil {
+		return err
+	}
+	if r == 'd' {
+		return ed.actionClearLine()
+	}
+	return nil
+}
+
+func (ed *Editor) actionViInsert() error {
+	ed.viInsert = true
+	ed.keymap = ed.emacsKeymap
+	return nil
+}
+
+func (ed *Editor) actionViAppend() error {
+	if ed.pos < len(ed.line) {
+		ed.pos++
+	}
+	return ed.actionViInsert()
+}
+
+// insertText inserts s at the cursor and advances the cursor past it.
+func (ed *Editor) insertText(s string) {
+	runes := []rune(s)
+	line := make([]rune, 0, len(ed.line)+len(runes))
+	line = append(line, ed.line[:ed.pos]...)
+	line = append(line, runes...)
+	line = append(line, ed.line[ed.pos:]...)
+	ed.line = line
+	ed.pos += len(runes)
+}
+
+// walkHistory moves backward (delta<0) or forward (delta>0) through
+// history, preserving the in-progress line as slot 0.
+func (ed *Editor) walkHistory(delta int) {
+	if delta < 0 {
+		if ed.histPos >= len(ed.histLines) {
+			ed.beep()
+			return
+		}
+		if ed.histPos == 0 {
+			ed.pendingLine = append([]rune(nil), ed.line...)
+		}
+		ed.histPos++
+		ed.line = []rune(ed.histLines[len(ed.histLines)-ed.histPos])
+	} else {
+		if ed.histPos == 0 {
+			ed.beep()
+			return
+		}
+		ed.histPos--
+		if ed.histPos == 0 {
+			ed.line = ed.pendingLine
+		} else {
+			ed.line = []rune(ed.histLines[len(ed.histLines)-ed.histPos])
+		}
+	}
+	ed.pos = len(ed.line)
+}
+
+// actionSearchHistory implements incremental reverse-i-search (Ctrl-R): it
+// reads keys until Enter (accept) or Ctrl-G/Esc (cancel), updating the
+// matched history entry on every keystroke by scanning ed.histLines
+// backwards from the current match.
+func (ed *Editor) actionSearchHistory() error {
+	original := append([]rune(nil), ed.line...)
+	pattern := ""
+	matched := string(original)
+	from := len(ed.histLines)
+
+	find := func(pattern string, from int) (string, int) {
+		if pattern == "" {
+			return matched, from
+		}
+		for i := from - 1; i >= 0; i-- {
+			if strings.Contains(ed.histLines[i], pattern) {
+				return ed.histLines[i], i
+			}
+		}
+		return matched, from
+	}
+
+	for {
+		searchPrompt := fmt.Sprintf("(reverse-i-search)'%s': ", pattern)
+		if err := ed.writer.refresh(searchPrompt, matched, "", len(matched), colsOf(ed), ed.file); err != nil {
+			return err
+		}
+
+		r, err := ed.stdin.next()
+		if err != nil {
+			return err
+		}
+
+		var key Key
+		if r == 0x1b {
+			next, err, ok := ed.stdin.nextTimeout(escTimeout)
+			if !ok {
+				// No follow-up byte arrived in time: a bare Esc press,
+				// not the start of an escape sequence.
+				ed.line, ed.pos = original, len(original)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			key, err = decodeEscapeRune(ed.stdin, next)
+			if err != nil {
+				return err
+			}
+		} else {
+			key, err = decodeKey(ed.stdin, r)
+			if err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case key == (Key{Rune: '\n'}):
+			ed.line, ed.pos = []rune(matched), len([]rune(matched))
+			ed.histPos = 0
+			return nil
+		case key == (Key{Rune: 'g', Mod: ModCtrl}): // ^G: cancel
+			ed.line, ed.pos = original, len(original)
+			return nil
+		case key == (Key{Rune: 'r', Mod: ModCtrl}): // ^R again: jump to the next older match
+			matched, from = find(pattern, from)
+		case key == (Key{Rune: 0x7f}): // Backspace
+			if l := len(pattern); l > 0 {
+				_, w := lastRuneLen(pattern)
+				pattern = pattern[:l-w]
+				from = len(ed.histLines)
+				matched, from = find(pattern, from)
+			} else {
+				ed.beep()
+			}
+		default:
+			if key.Mod == 0 && key.Rune >= 0 && unicode.IsGraphic(key.Rune) {
+				pattern += string(key.Rune)
+				from = len(ed.histLines)
+				matched, from = find(pattern, from)
+			}
+		}
+	}
+}
+
+func lastRuneLen(s string) (rune, int) {
+	r := []rune(s)
+	if len(r) == 0 {
+		return 0, 0
+	}
+	return r[len(r)-1], len(string(r[len(r)-1]))
+}
+
+func colsOf(ed *Editor) int {
+	cols, _ := ed.currentSize()
+	return cols
+}
+
+// actionComplete runs Tab completion at the cursor, inserting a single
+// candidate directly or rendering a candidate menu for the user to cycle
+// through with subsequent Tabs.
+func (ed *Editor) actionComplete() error {
+	return ed.doComplete()
+}
@@ -0,0 +1,221 @@
+package edit
+
+import (
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Candidate is a single completion candidate.
+type Candidate struct {
+	// Text is inserted into the line when the candidate is accepted.
+	Text string
+	// Display is shown in the candidate menu; defaults to Text if empty.
+	Display string
+	// Description is extra information shown alongside Display.
+	Description string
+}
+
+// Completer computes completion candidates for the line up to pos.
+type Completer interface {
+	// Complete returns the span [start, end) of line that is being
+	// completed, and the candidates that can replace it.
+	Complete(line string, pos int) (start, end int, candidates []Candidate)
+}
+
+type completerEntry struct {
+	prefix string
+	completer Completer
+}
+
+// RegisterCompleter registers c as the completer to use when the word
+// being completed starts with prefix. The empty prefix matches everything
+// and acts as the fallback completer.
+func (ed *Editor) RegisterCompleter(prefix string, c Completer) {
+	ed.completers = append(ed.completers, completerEntry{prefix, c})
+}
+
+// completerFor returns the most specific registered completer whose prefix
+// is a prefix of word, or nil if none match.
+func (ed *Editor) completerFor(word string) Completer {
+	var best *completerEntry
+	for i := range ed.completers {
+		e := &ed.completers[i]
+		if strings.HasPrefix(word, e.prefix) {
+			if best == nil || len(e.prefix) > len(best.prefix) {
+				best = e
+			}
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.completer
+}
+
+// wordStart finds the start of the whitespace-delimited word ending at pos.
+func wordStart(line string, pos int) int {
+	i := pos
+	for i > 0 {
+		r, w := utf8.DecodeLastRuneInString(line[:i])
+		if r == ' ' || r == '\t' {
+			break
+		}
+		i -= w
+	}
+	return i
+}
+
+// commonPrefix returns the longest common prefix of the Text fields of
+// candidates.
+func commonPrefix(candidates []Candidate) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+	prefix := candidates[0].Text
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c.Text, prefix) {
+			prefix = prefix[:len(prefix)-1]
+			if prefix == "" {
+				return ""
+			}
+		}
+	}
+	return prefix
+}
+
+// filenameCompleter completes filenames, expanding a leading ~ to the
+// user's home directory and quoting candidates that contain spaces.
+type filenameCompleter struct{}
+
+// NewFilenameCompleter returns a built-in Completer that completes
+// filenames relative to the current directory.
+func NewFilenameCompleter() Completer {
+	return filenameCompleter{}
+}
+
+func (filenameCompleter) Complete(line string, pos int) (int, int, []Candidate) {
+	start := wordStart(line, pos)
+	word := line[start:pos]
+
+	dir, base := "", word
+	if i := strings.LastIndexByte(word, '/'); i >= 0 {
+		dir, base = word[:i+1], word[i+1:]
+	}
+
+	lookupDir := dir
+	if strings.HasPrefix(lookupDir, "~/") || lookupDir == "~" {
+		if home := os.Getenv("HOME"); home != "" {
+			lookupDir = home + lookupDir[1:]
+		}
+	}
+	if lookupDir == "" {
+		lookupDir = "."
+	}
+
+	entries, err := os.ReadDir(lookupDir)
+	if err != nil {
+		return start, pos, nil
+	}
+
+	var candidates []Candidate
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) {
+			continue
+		}
+		text := dir + name
+		if entry.IsDir() {
+			text += "/"
+		}
+		if strings.ContainsAny(text, " \t") {
+			text = quoteFilename(text)
+		}
+		candidates = append(candidates, Candidate{Text: text, Display: name})
+	}
+	return start, pos, candidates
+}
+
+func quoteFilename(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r == ' ' || r == '\t' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// doComplete runs Tab completion at the current cursor position, inserting
+// a single candidate directly or rendering a candidate menu for the user to
+// cycle through with subsequent Tabs.
+func (ed *Editor) doComplete() error {
+	line := string(ed.line)
+	pos := len(string(ed.line[:ed.pos]))
+	completer := ed.completerFor(line[wordStart(line, pos):])
+	if completer == nil {
+		return nil
+	}
+
+	start, end, candidates := completer.Complete(line, pos)
+	if len(candidates) == 0 {
+		ed.beep()
+		return nil
+	}
+	if len(candidates) == 1 {
+		ed.setLine(line[:start] + candidates[0].Text + line[end:])
+		ed.pos = len([]rune(line[:start] + candidates[0].Text))
+		return nil
+	}
+
+	head, tail := line[:start], line[end:]
+	prefix := commonPrefix(candidates)
+	ed.setLine(head + prefix + tail)
+	ed.pos = len([]rune(head + prefix))
+
+	selected := -1
+	for {
+		cols, _ := ed.currentSize()
+		cursor := len(string(ed.line[:ed.pos]))
+		if err := ed.writer.refreshMenu(ed.prompt, string(ed.line), "", candidates, cursor, cols, ed.file); err != nil {
+			return err
+		}
+
+		menuCols, _ := candidateColumns(candidates, cols)
+
+		r, err := ed.stdin.next()
+		if err != nil {
+			return err
+		}
+		key, err := decodeKey(ed.stdin, r)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case key == (Key{Rune: '\t'}):
+			selected = (selected + 1) % len(candidates)
+		case key == (Key{Rune: Right}):
+			selected = (selected + 1) % len(candidates)
+		case key == (Key{Rune: Left}):
+			selected = ((selected-1)%len(candidates) + len(candidates)) % len(candidates)
+		case key == (Key{Rune: Down}):
+			selected = (selected + menuCols) % len(candidates)
+		case key == (Key{Rune: Up}):
+			selected = ((selected-menuCols)%len(candidates) + len(candidates)) % len(candidates)
+		default:
+			// Any other key dismisses the menu and is then processed
+			// normally, rather than being swallowed by the menu loop.
+			ed.pendingKey = &key
+			return nil
+		}
+		ed.setLine(head + candidates[selected].Text + tail)
+		ed.pos = len([]rune(head + candidates[selected].Text))
+	}
+}
+
+// setLine replaces the in-progress line.
+func (ed *Editor) setLine(s string) {
+	ed.line = []rune(s)
+}
@@ -5,16 +5,39 @@ import (
 	"os"
 	"fmt"
 	"bufio"
+	"sync/atomic"
 	"unicode"
-	"unicode/utf8"
 	"./tty"
 )
 
 // Editor keeps the status of the line editor.
 type Editor struct {
-	savedTermios *tty.Termios
+	savedState *tty.State
 	file *os.File
 	writer *writer
+	history History
+	completers []completerEntry
+
+	cols, rows int32 // current terminal size, accessed atomically
+	resizeCh chan struct{}
+
+	keymap Keymap
+	emacsKeymap Keymap
+	viNormalKeymap Keymap
+	viMode bool
+	viInsert bool
+
+	stdin *runeReader
+	pendingKey *Key
+	prompt string
+	line []rune
+	pos int
+	tip string
+
+	histLines []string
+	histPos int
+	pendingLine []rune
+	killRing []string
 }
 
 // LineRead is the result of ReadLine. Exactly one member is non-zero, making
@@ -28,26 +51,25 @@ type LineRead struct {
 // Init initializes an Editor on the terminal referenced by fd.
 func Init(file *os.File) (*Editor, error) {
 	fd := int(file.Fd())
-	term, err := tty.NewTermiosFromFd(fd)
+	saved, err := tty.MakeRaw(fd)
 	if err != nil {
-		return nil, fmt.Errorf("Can't get terminal attribute: %s", err)
+		return nil, fmt.Errorf("Can't set up terminal attribute: %s", err)
 	}
 
 	editor := &Editor{
-		savedTermios: term.Copy(),
+		savedState: saved,
 		file: file,
 		writer: newWriter(),
+		history: NewFileHistory(DefaultHistoryPath()),
+		resizeCh: make(chan struct{}, 1),
 	}
+	editor.RegisterCompleter("", NewFilenameCompleter())
+	editor.SetVi(false)
 
-	term.SetIcanon(false)
-	term.SetEcho(false)
-	term.SetMin(1)
-	term.SetTime(0)
+	editor.pollSize()
+	editor.watchResize()
 
-	err = term.ApplyToFd(fd)
-	if err != nil {
-		return nil, fmt.Errorf("Can't set up terminal attribute: %s", err)
-	}
+	editor.stdin = newRuneReader(bufio.NewReaderSize(editor.file, 0))
 
 	fmt.Fprint(editor.file, "\033[?7l")
 	return editor, nil
@@ -59,66 +81,145 @@ func (ed *Editor) Cleanup() error {
 	fmt.Fprint(ed.file, "\033[?7h")
 
 	fd := int(ed.file.Fd())
-	err := ed.savedTermios.ApplyToFd(fd)
+	err := tty.Restore(fd, ed.savedState)
 	if err != nil {
 		return fmt.Errorf("Can't restore terminal attribute of stdin: %s", err)
 	}
-	ed.savedTermios = nil
+	ed.savedState = nil
+
+	if ed.history != nil {
+		if err := ed.history.Save(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (ed *Editor) beep() {
 }
 
-func (ed *Editor) refresh(prompt, text, tip string) error {
-	return ed.writer.refresh(prompt, text, tip, ed.file)
+// currentSize returns the most recently observed terminal size.
+func (ed *Editor) currentSize() (cols, rows int) {
+	return int(atomic.LoadInt32(&ed.cols)), int(atomic.LoadInt32(&ed.rows))
+}
+
+// pollSize queries the terminal size and, if it changed, records it and
+// wakes up any pending ReadLine so it can re-render.
+func (ed *Editor) pollSize() {
+	cols, rows, err := tty.GetSize(int(ed.file.Fd()))
+	if err != nil {
+		return
+	}
+	oldCols, oldRows := ed.currentSize()
+	if cols == oldCols && rows == oldRows {
+		return
+	}
+	atomic.StoreInt32(&ed.cols, int32(cols))
+	atomic.StoreInt32(&ed.rows, int32(rows))
+	select {
+	case ed.resizeCh <- struct{}{}:
+	default:
+	}
+}
+
+// refresh redraws the prompt, the in-progress line and the tip, annotated
+// with a mode indicator when Vi keybindings are active.
+func (ed *Editor) refresh() error {
+	cols, _ := ed.currentSize()
+	tip := ed.tip
+	if ed.viMode {
+		mode := "[INSERT]"
+		if !ed.viInsert {
+			mode = "[NORMAL]"
+		}
+		if tip != "" {
+			tip = tip + " " + mode
+		} else {
+			tip = mode
+		}
+	}
+	cursor := len(string(ed.line[:ed.pos]))
+	return ed.writer.refresh(ed.prompt, string(ed.line), tip, cursor, cols, ed.file)
 }
 
 // ReadLine reads a line interactively.
 func (ed *Editor) ReadLine(prompt string) (lr LineRead) {
-	stdin := bufio.NewReaderSize(ed.file, 0)
-	line := ""
-	tip := ""
+	ed.prompt = prompt
+	ed.line = nil
+	ed.pos = 0
+	ed.tip = ""
+	ed.pendingLine = nil
+	ed.histPos = 0
+	if ed.history != nil {
+		ed.histLines, _ = ed.history.Load()
+	}
 
 	for {
-		err := ed.refresh(prompt, line, tip)
-		if err != nil {
+		if err := ed.refresh(); err != nil {
 			return LineRead{Err: err}
 		}
 
-		r, _, err := stdin.ReadRune()
-		if err != nil {
-			return LineRead{Err: err}
-		}
+		var key Key
+		if ed.pendingKey != nil {
+			key = *ed.pendingKey
+			ed.pendingKey = nil
+		} else {
+			var first rune
+			select {
+			case ev := <-ed.stdin.ch:
+				if ev.err != nil {
+					return LineRead{Err: ev.err}
+				}
+				first = ev.r
+			case <-ed.resizeCh:
+				continue
+			}
 
-		switch {
-		case r == '\n':
-			tip = ""
-			err := ed.refresh(prompt, line, tip)
+			var err error
+			key, err = decodeKey(ed.stdin, first)
 			if err != nil {
 				return LineRead{Err: err}
 			}
-			fmt.Fprintln(ed.file)
-			return LineRead{Line: line}
-		case r == 0x7f: // Backspace
-			if l := len(line); l > 0 {
-				_, w := utf8.DecodeLastRuneInString(line)
-				line = line[:l-w]
+		}
+
+		// A lone Esc in Vi insert mode is indistinguishable from a
+		// meta-prefixed key without a read timeout; treat it as "switch to
+		// normal mode, then process the following key as a command".
+		if ed.viMode && ed.viInsert && key.Mod&ModAlt != 0 {
+			ed.viInsert = false
+			ed.keymap = ed.viNormalKeymap
+			key = Key{Rune: key.Rune}
+		}
+
+		act, ok := ed.keymap[key]
+		if !ok {
+			if key.Mod == 0 && key.Rune >= 0 && unicode.IsGraphic(key.Rune) {
+				ed.insertText(string(key.Rune))
 			} else {
-				ed.beep()
+				ed.tip = fmt.Sprintf("Unbound key: %#x", key.Rune)
+			}
+			continue
+		}
+
+		ed.tip = ""
+		err := act(ed)
+		switch err {
+		case nil:
+			// keep going
+		case errAcceptLine:
+			if err := ed.refresh(); err != nil {
+				return LineRead{Err: err}
 			}
-		case r == 0x15: // ^U
-			line = ""
-		case r == 0x4 && len(line) == 0: // ^D
+			fmt.Fprintln(ed.file)
+			line := string(ed.line)
+			if ed.history != nil && line != "" {
+				ed.history.Append(line)
+			}
+			return LineRead{Line: line}
+		case errEOF:
 			return LineRead{Eof: true}
-		case r == 0x2: // ^B
-			fmt.Fprintf(ed.file, "\033[D")
-		case r == 0x6: // ^F
-			fmt.Fprintf(ed.file, "\033[C")
-		case unicode.IsGraphic(r):
-			line += string(r)
 		default:
-			tip = fmt.Sprintf("Non-graphic: %#x", r)
+			return LineRead{Err: err}
 		}
 	}
 }
@@ -0,0 +1,109 @@
+package edit
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// History is the interface implemented by history storage backends.
+type History interface {
+	// Append adds a line to the history, skipping it if it is identical to
+	// the most recently appended line.
+	Append(line string)
+	// Load reads all history lines from the backing store.
+	Load() ([]string, error)
+	// Save persists the in-memory history to the backing store.
+	Save() error
+}
+
+// fileHistory is a History backed by a plain text file, one entry per line.
+type fileHistory struct {
+	path   string
+	lines  []string
+	loaded bool
+}
+
+// NewFileHistory returns a History that reads from and writes to path.
+func NewFileHistory(path string) History {
+	return &fileHistory{path: path}
+}
+
+// DefaultHistoryPath returns the default path used for persistent history,
+// $HOME/.elvish/history.
+func DefaultHistoryPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".elvish/history")
+}
+
+func (h *fileHistory) Append(line string) {
+	if n := len(h.lines); n > 0 && h.lines[n-1] == line {
+		return
+	}
+	h.lines = append(h.lines, line)
+}
+
+// Load reads the history file the first time it is called. Later calls
+// return the in-memory lines as-is, so that entries appended earlier in
+// the session (which haven't been Saved to disk yet) aren't lost by
+// re-reading the file out from under them.
+func (h *fileHistory) Load() ([]string, error) {
+	if h.loaded {
+		return h.lines, nil
+	}
+	h.loaded = true
+
+	f, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't open history file: %s", err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read history file: %s", err)
+	}
+	h.lines = lines
+	return lines, nil
+}
+
+func (h *fileHistory) Save() error {
+	dir := filepath.Dir(h.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("can't create history directory: %s", err)
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".history")
+	if err != nil {
+		return fmt.Errorf("can't create temporary history file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, line := range h.lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			tmp.Close()
+			return fmt.Errorf("can't write history file: %s", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("can't write history file: %s", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("can't write history file: %s", err)
+	}
+
+	if err := os.Rename(tmp.Name(), h.path); err != nil {
+		return fmt.Errorf("can't replace history file: %s", err)
+	}
+	return nil
+}
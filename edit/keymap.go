@@ -0,0 +1,263 @@
+package edit
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Mod is a bitmask of modifier keys.
+type Mod uint8
+
+const (
+	ModCtrl Mod = 1 << iota
+	ModAlt
+)
+
+// Named keys, encoded as negative rune values so they never collide with
+// an actual Unicode code point.
+const (
+	Up rune = -(iota + 1)
+	Down
+	Left
+	Right
+	Home
+	End
+	Delete
+	PasteStart
+	PasteEnd
+)
+
+// Key identifies a single keypress, decoded from the raw bytes the
+// terminal sends.
+type Key struct {
+	Rune rune
+	Mod  Mod
+}
+
+// Action is a function bound to a Key; it is invoked with the Editor so it
+// can inspect and mutate editing state.
+type Action func(*Editor) error
+
+// Keymap maps keys to the actions they trigger.
+type Keymap map[Key]Action
+
+// errAcceptLine and errEOF are sentinel errors returned by actions to tell
+// ReadLine's dispatch loop to stop, rather than being genuine failures.
+var (
+	errAcceptLine = errors.New("accept line")
+	errEOF        = errors.New("eof")
+)
+
+// Bind registers act as the action for key in the Editor's active keymap.
+func (ed *Editor) Bind(key Key, act Action) {
+	if ed.keymap == nil {
+		ed.keymap = Keymap{}
+	}
+	ed.keymap[key] = act
+}
+
+// SetVi switches the Editor between Emacs-style (the default) and Vi-style
+// keybindings. Vi mode starts in insert mode; Esc switches to normal mode,
+// and 'i'/'a' switch back to insert mode.
+func (ed *Editor) SetVi(v bool) {
+	if ed.emacsKeymap == nil {
+		ed.emacsKeymap = defaultEmacsKeymap()
+	}
+	ed.viMode = v
+	if v {
+		if ed.viNormalKeymap == nil {
+			ed.viNormalKeymap = defaultViNormalKeymap()
+		}
+		ed.viInsert = true
+	}
+	ed.keymap = ed.emacsKeymap
+}
+
+// decodeKey assembles a Key from raw terminal bytes, given that first has
+// already been read off the wire. It understands CSI sequences
+// (\033[A, \033[3~, ...), meta-prefixed Alt keys (\033x), and bracketed
+// paste (\033[200~...\033[201~, returned as the PasteStart/PasteEnd keys).
+func decodeKey(rr *runeReader, first rune) (Key, error) {
+	switch {
+	case first == 0x1b:
+		return decodeEscape(rr)
+	case first == '\t':
+		return Key{Rune: '\t'}, nil
+	case first == '\n' || first == '\r':
+		// Raw mode disables ICRNL, so a real terminal's Enter key arrives
+		// as CR (0x0D); treat it the same as the LF the keymap binds.
+		return Key{Rune: '\n'}, nil
+	case first == 0x7f:
+		return Key{Rune: 0x7f}, nil
+	case first >= 1 && first <= 26:
+		return Key{Rune: rune('a' + first - 1), Mod: ModCtrl}, nil
+	default:
+		return Key{Rune: first}, nil
+	}
+}
+
+func decodeEscape(rr *runeReader) (Key, error) {
+	r, err := rr.next()
+	if err != nil {
+		return Key{}, err
+	}
+	return decodeEscapeRune(rr, r)
+}
+
+// decodeEscapeRune is decodeEscape for the common case where the byte
+// following Esc has already been read off the wire (e.g. by a caller that
+// peeked at it to tell a bare Esc apart from the start of a sequence).
+func decodeEscapeRune(rr *runeReader, r rune) (Key, error) {
+	if r == '[' {
+		return decodeCSI(rr)
+	}
+	if r >= 1 && r <= 26 {
+		return Key{Rune: rune('a' + r - 1), Mod: ModCtrl | ModAlt}, nil
+	}
+	return Key{Rune: r, Mod: ModAlt}, nil
+}
+
+func decodeCSI(rr *runeReader) (Key, error) {
+	var num strings.Builder
+	r, err := rr.next()
+	if err != nil {
+		return Key{}, err
+	}
+	for r >= '0' && r <= '9' {
+		num.WriteRune(r)
+		r, err = rr.next()
+		if err != nil {
+			return Key{}, err
+		}
+	}
+	if r == '~' {
+		switch num.String() {
+		case "3":
+			return Key{Rune: Delete}, nil
+		case "200":
+			return Key{Rune: PasteStart}, nil
+		case "201":
+			return Key{Rune: PasteEnd}, nil
+		}
+		return Key{}, nil
+	}
+	switch r {
+	case 'A':
+		return Key{Rune: Up}, nil
+	case 'B':
+		return Key{Rune: Down}, nil
+	case 'C':
+		return Key{Rune: Right}, nil
+	case 'D':
+		return Key{Rune: Left}, nil
+	case 'H':
+		return Key{Rune: Home}, nil
+	case 'F':
+		return Key{Rune: End}, nil
+	}
+	return Key{}, nil
+}
+
+// readPaste reads literal text until a bracketed-paste end marker, for
+// inserting into the line verbatim without triggering keybindings.
+func readPaste(rr *runeReader) (string, error) {
+	var buf strings.Builder
+	for {
+		r, err := rr.next()
+		if err != nil {
+			return buf.String(), err
+		}
+		if r == 0x1b {
+			key, err := decodeEscape(rr)
+			if err != nil {
+				return buf.String(), err
+			}
+			if key.Rune == PasteEnd {
+				return buf.String(), nil
+			}
+			continue
+		}
+		buf.WriteRune(r)
+	}
+}
+
+func isWordSep(r rune) bool {
+	return unicode.IsSpace(r)
+}
+
+// wordStartRunes returns the start of the word ending at pos.
+func wordStartRunes(line []rune, pos int) int {
+	i := pos
+	for i > 0 && isWordSep(line[i-1]) {
+		i--
+	}
+	for i > 0 && !isWordSep(line[i-1]) {
+		i--
+	}
+	return i
+}
+
+// wordEndRunes returns the end of the next word starting at or after pos.
+func wordEndRunes(line []rune, pos int) int {
+	i, n := pos, len(line)
+	for i < n && isWordSep(line[i]) {
+		i++
+	}
+	for i < n && !isWordSep(line[i]) {
+		i++
+	}
+	return i
+}
+
+func defaultEmacsKeymap() Keymap {
+	return Keymap{
+		Key{Rune: '\n'}: (*Editor).actionAcceptLine,
+		Key{Rune: 0x7f}: (*Editor).actionDeleteBack,
+		Key{Rune: '\t'}: (*Editor).actionComplete,
+
+		Key{Rune: 'a', Mod: ModCtrl}: (*Editor).actionMoveLineStart,
+		Key{Rune: 'e', Mod: ModCtrl}: (*Editor).actionMoveLineEnd,
+		Key{Rune: 'b', Mod: ModCtrl}: (*Editor).actionMoveLeft,
+		Key{Rune: 'f', Mod: ModCtrl}: (*Editor).actionMoveRight,
+		Key{Rune: 'p', Mod: ModCtrl}: (*Editor).actionHistoryPrev,
+		Key{Rune: 'n', Mod: ModCtrl}: (*Editor).actionHistoryNext,
+		Key{Rune: 'd', Mod: ModCtrl}: (*Editor).actionEOFOrDeleteForward,
+		Key{Rune: 'u', Mod: ModCtrl}: (*Editor).actionClearLine,
+		Key{Rune: 'k', Mod: ModCtrl}: (*Editor).actionKillToEnd,
+		Key{Rune: 'w', Mod: ModCtrl}: (*Editor).actionKillWordBack,
+		Key{Rune: 'y', Mod: ModCtrl}: (*Editor).actionYank,
+		Key{Rune: 'l', Mod: ModCtrl}: (*Editor).actionClearScreen,
+		Key{Rune: 'r', Mod: ModCtrl}: (*Editor).actionSearchHistory,
+
+		Key{Rune: 'b', Mod: ModAlt}: (*Editor).actionBackWord,
+		Key{Rune: 'f', Mod: ModAlt}: (*Editor).actionForwardWord,
+
+		Key{Rune: Up}:         (*Editor).actionHistoryPrev,
+		Key{Rune: Down}:       (*Editor).actionHistoryNext,
+		Key{Rune: Left}:       (*Editor).actionMoveLeft,
+		Key{Rune: Right}:      (*Editor).actionMoveRight,
+		Key{Rune: Home}:       (*Editor).actionMoveLineStart,
+		Key{Rune: End}:        (*Editor).actionMoveLineEnd,
+		Key{Rune: Delete}:     (*Editor).actionDeleteForward,
+		Key{Rune: PasteStart}: (*Editor).actionPaste,
+	}
+}
+
+func defaultViNormalKeymap() Keymap {
+	return Keymap{
+		Key{Rune: '\n'}: (*Editor).actionAcceptLine,
+		Key{Rune: 'h'}:  (*Editor).actionMoveLeft,
+		Key{Rune: 'l'}:  (*Editor).actionMoveRight,
+		Key{Rune: 'j'}:  (*Editor).actionHistoryNext,
+		Key{Rune: 'k'}:  (*Editor).actionHistoryPrev,
+		Key{Rune: 'w'}:  (*Editor).actionForwardWord,
+		Key{Rune: 'b'}:  (*Editor).actionBackWord,
+		Key{Rune: '0'}:  (*Editor).actionMoveLineStart,
+		Key{Rune: '$'}:  (*Editor).actionMoveLineEnd,
+		Key{Rune: 'x'}:  (*Editor).actionDeleteForward,
+		Key{Rune: 'd'}:  (*Editor).actionViPendingD,
+		Key{Rune: 'i'}:  (*Editor).actionViInsert,
+		Key{Rune: 'a'}:  (*Editor).actionViAppend,
+	}
+}
@@ -0,0 +1,54 @@
+package edit
+
+import (
+	"bufio"
+	"time"
+)
+
+// runeEvent is a single rune read from the terminal, or the error that
+// ended the read.
+type runeEvent struct {
+	r   rune
+	err error
+}
+
+// runeReader reads runes from a bufio.Reader on its own goroutine and
+// delivers them over a channel, so that ReadLine's main loop can select
+// between incoming keystrokes and other events such as a terminal resize.
+type runeReader struct {
+	ch chan runeEvent
+}
+
+func newRuneReader(stdin *bufio.Reader) *runeReader {
+	rr := &runeReader{ch: make(chan runeEvent)}
+	go func() {
+		for {
+			r, _, err := stdin.ReadRune()
+			rr.ch <- runeEvent{r, err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return rr
+}
+
+// next blocks until the next rune (or read error) is available.
+func (rr *runeReader) next() (rune, error) {
+	ev := <-rr.ch
+	return ev.r, ev.err
+}
+
+// nextTimeout waits up to d for the next rune, reporting ok=false if none
+// arrived in time. It's used to tell a bare Esc press apart from the start
+// of an escape sequence, whose remaining bytes arrive as a burst right
+// behind it; a rune that times out isn't lost, it's simply picked up by
+// the next call to next() or nextTimeout().
+func (rr *runeReader) nextTimeout(d time.Duration) (r rune, err error, ok bool) {
+	select {
+	case ev := <-rr.ch:
+		return ev.r, ev.err, true
+	case <-time.After(d):
+		return 0, nil, false
+	}
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package edit
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchResize spawns a goroutine that re-polls the terminal size whenever
+// SIGWINCH is received.
+func (ed *Editor) watchResize() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+	go func() {
+		for range sigs {
+			ed.pollSize()
+		}
+	}()
+}
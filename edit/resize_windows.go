@@ -0,0 +1,21 @@
+//go:build windows
+
+package edit
+
+import "time"
+
+// resizePollInterval is how often the console size is polled on Windows,
+// which has no SIGWINCH equivalent.
+const resizePollInterval = 250 * time.Millisecond
+
+// watchResize spawns a goroutine that periodically re-polls the console
+// size, since Windows has no SIGWINCH to notify us of a resize.
+func (ed *Editor) watchResize() {
+	go func() {
+		ticker := time.NewTicker(resizePollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ed.pollSize()
+		}
+	}()
+}
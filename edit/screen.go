@@ -0,0 +1,135 @@
+package edit
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// cell is a single character position on screen, with an associated style
+// (an SGR escape sequence, or "" for the default style).
+type cell struct {
+	r     rune
+	style string
+}
+
+// frame is a fully-rendered grid of cells, along with where the cursor
+// should end up, both relative to the top-left of the editable region.
+type frame struct {
+	rows                 [][]cell
+	cursorRow, cursorCol int
+}
+
+func newFrame(lines []string, cursorRow, cursorCol int) frame {
+	rows := make([][]cell, len(lines))
+	for i, line := range lines {
+		cells := make([]cell, 0, len(line))
+		for _, r := range line {
+			cells = append(cells, cell{r: r})
+		}
+		rows[i] = cells
+	}
+	return frame{rows: rows, cursorRow: cursorRow, cursorCol: cursorCol}
+}
+
+// screen retains the frame last written to the terminal, so that refresh
+// can diff against it and only send the bytes needed to reconcile the two,
+// instead of re-emitting the whole prompt and line on every keystroke.
+type screen struct {
+	cur frame
+}
+
+func newScreen() *screen {
+	return &screen{}
+}
+
+// refresh reconciles the terminal with next, writing only the cell runs
+// that differ from the last frame, and performs a single Write to file.
+func (s *screen) refresh(next frame, file *os.File) error {
+	var buf strings.Builder
+	buf.WriteString("\033[?25l") // hide cursor while redrawing
+
+	if s.cur.cursorRow > 0 {
+		fmt.Fprintf(&buf, "\033[%dA", s.cur.cursorRow)
+	}
+	buf.WriteString("\r")
+
+	maxRows := len(next.rows)
+	if len(s.cur.rows) > maxRows {
+		maxRows = len(s.cur.rows)
+	}
+	if maxRows == 0 {
+		maxRows = 1
+	}
+
+	for y := 0; y < maxRows; y++ {
+		if y > 0 {
+			buf.WriteString("\r\n")
+		}
+
+		var oldRow, newRow []cell
+		if y < len(s.cur.rows) {
+			oldRow = s.cur.rows[y]
+		}
+		if y < len(next.rows) {
+			newRow = next.rows[y]
+		}
+
+		if rowsEqual(oldRow, newRow) {
+			continue
+		}
+		start := firstDiff(oldRow, newRow)
+		if start > 0 {
+			fmt.Fprintf(&buf, "\033[%dC", start)
+		}
+		for _, c := range newRow[start:] {
+			buf.WriteRune(c.r)
+		}
+		if len(newRow) < len(oldRow) {
+			buf.WriteString("\033[K")
+		}
+	}
+
+	buf.WriteString("\r")
+	if d := next.cursorRow - (maxRows - 1); d > 0 {
+		fmt.Fprintf(&buf, "\033[%dB", d)
+	} else if d < 0 {
+		fmt.Fprintf(&buf, "\033[%dA", -d)
+	}
+	if next.cursorCol > 0 {
+		fmt.Fprintf(&buf, "\033[%dC", next.cursorCol)
+	}
+
+	buf.WriteString("\033[?25h") // show cursor again
+
+	s.cur = next
+	_, err := file.WriteString(buf.String())
+	return err
+}
+
+func rowsEqual(a, b []cell) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// firstDiff returns the index of the first cell at which a and b differ,
+// or the length of the shorter row if one is a prefix of the other.
+func firstDiff(a, b []cell) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
@@ -0,0 +1,120 @@
+package edit
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// render runs a sequence of line edits through a single screen and returns
+// the bytes written for each step, so tests can assert that a refresh only
+// sends what's needed to reconcile the previous frame with the next one.
+func render(t *testing.T, s *screen, lines []string, cursorRow, cursorCol int) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %s", err)
+	}
+	defer r.Close()
+
+	if err := s.refresh(newFrame(lines, cursorRow, cursorCol), w); err != nil {
+		t.Fatalf("refresh: %s", err)
+	}
+	w.Close()
+
+	var buf strings.Builder
+	chunk := make([]byte, 4096)
+	for {
+		n, err := r.Read(chunk)
+		buf.Write(chunk[:n])
+		if err != nil {
+			break
+		}
+	}
+	return buf.String()
+}
+
+// countRunes reports how many non-escape-sequence runes appear in out,
+// as a proxy for how much actual line content was (re)transmitted.
+func countRunes(out string) int {
+	n := 0
+	for i := 0; i < len(out); i++ {
+		if out[i] == 0x1b {
+			for i < len(out) && out[i] != 'A' && out[i] != 'B' && out[i] != 'C' && out[i] != 'D' &&
+				out[i] != 'h' && out[i] != 'l' && out[i] != 'K' && out[i] != 'J' {
+				i++
+			}
+			continue
+		}
+		if out[i] == '\r' || out[i] == '\n' {
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+func TestScreenRefreshInsert(t *testing.T) {
+	s := newScreen()
+	render(t, s, []string{"$ foo"}, 0, 5)
+
+	out := render(t, s, []string{"$ foox"}, 0, 6)
+	if n := countRunes(out); n != 1 {
+		t.Errorf("inserting one rune sent %d rune(s) of content, want 1; output: %q", n, out)
+	}
+}
+
+func TestScreenRefreshBackspace(t *testing.T) {
+	s := newScreen()
+	render(t, s, []string{"$ foox"}, 0, 6)
+
+	out := render(t, s, []string{"$ foo"}, 0, 5)
+	if !strings.Contains(out, "\033[K") {
+		t.Errorf("deleting the trailing rune should clear to end of line; output: %q", out)
+	}
+	if n := countRunes(out); n != 0 {
+		t.Errorf("backspace with no new content sent %d rune(s), want 0; output: %q", n, out)
+	}
+}
+
+func TestScreenRefreshCursorMove(t *testing.T) {
+	s := newScreen()
+	render(t, s, []string{"$ foo"}, 0, 5)
+
+	out := render(t, s, []string{"$ foo"}, 0, 2)
+	if n := countRunes(out); n != 0 {
+		t.Errorf("moving the cursor with unchanged text sent %d rune(s), want 0; output: %q", n, out)
+	}
+}
+
+// leadingReposition returns the cursor-positioning bytes refresh emits
+// before it starts writing row content, i.e. everything up to (but not
+// including) the first '\r' that returns to column 0 for the first row.
+func leadingReposition(out string) string {
+	if i := strings.IndexByte(out, '\r'); i >= 0 {
+		return out[:i]
+	}
+	return out
+}
+
+func TestScreenRefreshMovesUpFromPriorCursorRow(t *testing.T) {
+	s := newScreen()
+	// Prompt row plus a candidate menu row below it; the cursor stays on
+	// the prompt row while the menu is shown.
+	render(t, s, []string{"$ foo", "candidate-a  candidate-b"}, 0, 5)
+
+	out := render(t, s, []string{"$ food", "candidate-a  candidate-b"}, 0, 6)
+	if lead := leadingReposition(out); strings.Contains(lead, "\033[1A") {
+		t.Errorf("cursor was on row 0, not the last row; refresh should not move up before redrawing; leading bytes: %q (full output: %q)", lead, out)
+	}
+}
+
+func TestScreenRefreshUnrelatedRowsUntouched(t *testing.T) {
+	s := newScreen()
+	render(t, s, []string{"$ foo", "candidate-a  candidate-b"}, 0, 5)
+
+	out := render(t, s, []string{"$ food", "candidate-a  candidate-b"}, 0, 6)
+	if n := countRunes(out); n != 1 {
+		t.Errorf("editing row 0 sent %d rune(s) of content, want 1 (row 1 unchanged); output: %q", n, out)
+	}
+}
@@ -0,0 +1,173 @@
+package edit
+
+import (
+	"os"
+	"strings"
+	"unicode"
+)
+
+// writer is responsible for rendering the prompt, the current line, and any
+// auxiliary information (tip, candidate menu) to the terminal, wrapping at
+// the terminal's current width. It composes the display into a frame and
+// hands it to a screen, which does the actual (diffed) drawing.
+type writer struct {
+	screen *screen
+}
+
+func newWriter() *writer {
+	return &writer{screen: newScreen()}
+}
+
+// refresh redraws the prompt, text and tip, wrapping at cols columns.
+// cursor is the byte offset into text where the cursor should end up.
+func (w *writer) refresh(prompt, text, tip string, cursor, cols int, file *os.File) error {
+	return w.refreshMenu(prompt, text, tip, nil, cursor, cols, file)
+}
+
+// refreshMenu is like refresh, but additionally renders a candidate menu
+// below the prompt line when candidates is non-empty.
+func (w *writer) refreshMenu(prompt, text, tip string, candidates []Candidate, cursor, cols int, file *os.File) error {
+	if cols <= 0 {
+		cols = 80
+	}
+	if cursor < 0 || cursor > len(text) {
+		cursor = len(text)
+	}
+
+	lines := wrapLines(prompt+text, cols)
+	if tip != "" {
+		lines = append(lines, wrapLines(tip, cols)...)
+	}
+	lines = append(lines, layoutCandidates(candidates, cols)...)
+
+	cursorRow, cursorCol := cursorPosition(prompt+text[:cursor], cols)
+
+	return w.screen.refresh(newFrame(lines, cursorRow, cursorCol), file)
+}
+
+// runeWidth returns the number of terminal columns a rune occupies: 0 for
+// non-printable runes, 2 for wide East Asian runes, 1 otherwise.
+func runeWidth(r rune) int {
+	if !unicode.IsPrint(r) {
+		return 0
+	}
+	if isWide(r) {
+		return 2
+	}
+	return 1
+}
+
+// isWide reports whether r falls in one of the common East Asian Wide or
+// Fullwidth Unicode ranges.
+func isWide(r rune) bool {
+	switch {
+	case r >= 0x1100 && r <= 0x115F, // Hangul Jamo
+		r >= 0x2E80 && r <= 0xA4CF, // CJK radicals .. Yi
+		r >= 0xAC00 && r <= 0xD7A3, // Hangul syllables
+		r >= 0xF900 && r <= 0xFAFF, // CJK compatibility ideographs
+		r >= 0xFF00 && r <= 0xFF60, // Fullwidth forms
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x20000 && r <= 0x3FFFD: // CJK extension planes
+		return true
+	}
+	return false
+}
+
+// wrapLines splits s into display lines no wider than cols columns.
+func wrapLines(s string, cols int) []string {
+	if cols <= 0 {
+		return []string{s}
+	}
+	var lines []string
+	var cur strings.Builder
+	col := 0
+	for _, r := range s {
+		w := runeWidth(r)
+		if col+w > cols && col > 0 {
+			lines = append(lines, cur.String())
+			cur.Reset()
+			col = 0
+		}
+		cur.WriteRune(r)
+		col += w
+	}
+	lines = append(lines, cur.String())
+	return lines
+}
+
+// cursorPosition returns the (row, col) the cursor ends up at after
+// rendering s wrapped at cols columns, both 0-based.
+func cursorPosition(s string, cols int) (row, col int) {
+	if cols <= 0 {
+		cols = 80
+	}
+	for _, r := range s {
+		w := runeWidth(r)
+		if col+w > cols && col > 0 {
+			row++
+			col = 0
+		}
+		col += w
+	}
+	return row, col
+}
+
+// candidateColumns returns the number of columns the candidate menu is
+// laid out in for the given width, and the labels to lay out, so that
+// callers can translate Up/Down keys into the same grid layoutCandidates
+// draws.
+func candidateColumns(candidates []Candidate, width int) (cols int, labels []string) {
+	if len(candidates) == 0 {
+		return 1, nil
+	}
+	labels = make([]string, len(candidates))
+	colWidth := 0
+	for i, c := range candidates {
+		label := c.Display
+		if label == "" {
+			label = c.Text
+		}
+		labels[i] = label
+		if len(label) > colWidth {
+			colWidth = len(label)
+		}
+	}
+	colWidth += 2 // padding between columns
+
+	cols = width / colWidth
+	if cols < 1 {
+		cols = 1
+	}
+	return cols, labels
+}
+
+// layoutCandidates lays out candidates in columns sized to fit within
+// width, one candidate's Display (or Text, if Display is empty) per cell.
+func layoutCandidates(candidates []Candidate, width int) []string {
+	if len(candidates) == 0 {
+		return nil
+	}
+	cols, labels := candidateColumns(candidates, width)
+	colWidth := 0
+	for _, label := range labels {
+		if len(label) > colWidth {
+			colWidth = len(label)
+		}
+	}
+	colWidth += 2 // padding between columns
+
+	var rows []string
+	for i := 0; i < len(labels); i += cols {
+		end := i + cols
+		if end > len(labels) {
+			end = len(labels)
+		}
+		var row strings.Builder
+		for _, label := range labels[i:end] {
+			row.WriteString(label)
+			row.WriteString(strings.Repeat(" ", colWidth-len(label)))
+		}
+		rows = append(rows, row.String())
+	}
+	return rows
+}
@@ -0,0 +1,54 @@
+//go:build unix
+
+package tty
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// state holds the saved termios on Unix.
+type state struct {
+	termios unix.Termios
+}
+
+func makeRaw(fd int) (*State, error) {
+	term, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, fmt.Errorf("can't get termios: %s", err)
+	}
+	saved := *term
+
+	raw := *term
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP |
+		unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, fmt.Errorf("can't set termios: %s", err)
+	}
+	return &State{state{saved}}, nil
+}
+
+func restore(fd int, s *State) error {
+	return unix.IoctlSetTermios(fd, ioctlSetTermios, &s.state.termios)
+}
+
+func isTerminal(fd int) bool {
+	_, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	return err == nil
+}
+
+func getSize(fd int) (cols, rows int, err error) {
+	ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, fmt.Errorf("can't get window size: %s", err)
+	}
+	return int(ws.Col), int(ws.Row), nil
+}
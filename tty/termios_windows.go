@@ -0,0 +1,73 @@
+//go:build windows
+
+package tty
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// state holds the saved console mode for both the console's input and
+// output handles.
+type state struct {
+	inHandle, outHandle windows.Handle
+	inMode, outMode     uint32
+}
+
+func makeRaw(fd int) (*State, error) {
+	inHandle := windows.Handle(fd)
+	outHandle, err := windows.GetStdHandle(windows.STD_OUTPUT_HANDLE)
+	if err != nil {
+		return nil, fmt.Errorf("can't get stdout handle: %s", err)
+	}
+
+	var inMode, outMode uint32
+	if err := windows.GetConsoleMode(inHandle, &inMode); err != nil {
+		return nil, fmt.Errorf("can't get console input mode: %s", err)
+	}
+	if err := windows.GetConsoleMode(outHandle, &outMode); err != nil {
+		return nil, fmt.Errorf("can't get console output mode: %s", err)
+	}
+	saved := state{inHandle, outHandle, inMode, outMode}
+
+	rawIn := inMode &^ (windows.ENABLE_LINE_INPUT | windows.ENABLE_ECHO_INPUT |
+		windows.ENABLE_PROCESSED_INPUT)
+	rawIn |= windows.ENABLE_VIRTUAL_TERMINAL_INPUT
+	if err := windows.SetConsoleMode(inHandle, rawIn); err != nil {
+		return nil, fmt.Errorf("can't set console input mode: %s", err)
+	}
+
+	rawOut := outMode | windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING
+	if err := windows.SetConsoleMode(outHandle, rawOut); err != nil {
+		windows.SetConsoleMode(inHandle, inMode)
+		return nil, fmt.Errorf("can't set console output mode: %s", err)
+	}
+
+	return &State{saved}, nil
+}
+
+func restore(fd int, s *State) error {
+	if err := windows.SetConsoleMode(s.state.inHandle, s.state.inMode); err != nil {
+		return fmt.Errorf("can't restore console input mode: %s", err)
+	}
+	if err := windows.SetConsoleMode(s.state.outHandle, s.state.outMode); err != nil {
+		return fmt.Errorf("can't restore console output mode: %s", err)
+	}
+	return nil
+}
+
+func isTerminal(fd int) bool {
+	var mode uint32
+	return windows.GetConsoleMode(windows.Handle(fd), &mode) == nil
+}
+
+func getSize(fd int) (cols, rows int, err error) {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(fd), &info); err != nil {
+		return 0, 0, fmt.Errorf("can't get console screen buffer info: %s", err)
+	}
+	cols = int(info.Window.Right-info.Window.Left) + 1
+	rows = int(info.Window.Bottom-info.Window.Top) + 1
+	return cols, rows, nil
+}
@@ -0,0 +1,33 @@
+// Package tty provides a portable abstraction over terminal raw-mode
+// control and window size queries, with platform-specific implementations
+// for Unix and Windows.
+package tty
+
+// State holds a terminal's mode prior to MakeRaw, so it can later be
+// restored by Restore.
+type State struct {
+	state state
+}
+
+// MakeRaw puts the terminal referenced by fd into raw mode, returning its
+// previous state so it can be restored later.
+func MakeRaw(fd int) (*State, error) {
+	return makeRaw(fd)
+}
+
+// Restore restores the terminal referenced by fd to the state it was in
+// before MakeRaw was called.
+func Restore(fd int, s *State) error {
+	return restore(fd, s)
+}
+
+// IsTerminal reports whether fd refers to a terminal.
+func IsTerminal(fd int) bool {
+	return isTerminal(fd)
+}
+
+// GetSize returns the number of columns and rows of the terminal
+// referenced by fd.
+func GetSize(fd int) (cols, rows int, err error) {
+	return getSize(fd)
+}